@@ -3,14 +3,18 @@ package command
 import (
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
+	"os"
+	"reflect"
 	"strings"
 
 	"github.com/hashicorp/packer/fix"
 	"github.com/hashicorp/packer/packer"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/hcl/v2"
 	"github.com/posener/complete"
 )
 
@@ -18,6 +22,72 @@ type ValidateCommand struct {
 	Meta
 }
 
+// ValidateArgs are the arguments specific to the validate command, on top
+// of the flags common to every command (vars, build filters, ...).
+type ValidateArgs struct {
+	MetaArgs
+	Path       string
+	SyntaxOnly bool
+	// Format controls how validation results are rendered: "text" (the
+	// default, human readable), "json", or "sarif".
+	Format string
+	// CheckReferences opts in to verifying that external references
+	// (iso_url, checksum_url, provisioner scripts, http_directory) in the
+	// template actually resolve.
+	CheckReferences bool
+	// StrictReferences promotes broken external references from warnings
+	// to errors. Only takes effect when CheckReferences is set.
+	StrictReferences bool
+	// Fix rewrites the JSON template in place with the output of the
+	// fixers, same as `packer fix` would produce.
+	Fix bool
+}
+
+func (va *ValidateArgs) AddFlagSets(flags *flag.FlagSet) {
+	flags.BoolVar(&va.SyntaxOnly, "syntax-only", false, "check syntax only")
+	flags.StringVar(&va.Format, "format", "text", "output format: text, json, or sarif")
+	flags.BoolVar(&va.CheckReferences, "check-references", false, "verify that external references (iso_url, checksum_url, provisioner scripts, http_directory) resolve")
+	flags.BoolVar(&va.StrictReferences, "strict-references", false, "treat unreachable external references as errors instead of warnings")
+	flags.BoolVar(&va.Fix, "fix", false, "rewrite the JSON template in place with the output of the fixers")
+}
+
+// validateDiagnostic is the JSON/SARIF-friendly representation of a single
+// diagnostic produced while validating a template.
+type validateDiagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail,omitempty"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	// Source is the builder/provisioner block the diagnostic came from,
+	// e.g. "source.amazon-ebs.example". hcl.Diagnostics don't carry a
+	// stable reference back to the block that produced them, so this is
+	// derived on a best-effort basis from the diagnostic's expression (for
+	// conventional "source.TYPE.NAME" / "build.*" traversals) and is left
+	// empty when it can't be determined.
+	Source string `json:"source,omitempty"`
+}
+
+// validateFixDiff is a single fixable configuration difference, expressed
+// as a JSON Pointer path into the template along with its before/after
+// values.
+type validateFixDiff struct {
+	Fixer  string      `json:"fixer,omitempty"`
+	Path   string      `json:"path"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// validateResult is the machine-readable output of `packer validate` for
+// the json and sarif formats.
+type validateResult struct {
+	Success       bool                 `json:"success"`
+	Diagnostics   []validateDiagnostic `json:"diagnostics,omitempty"`
+	FixableDiffs  []validateFixDiff    `json:"fixable_diffs,omitempty"`
+	AppliedFixers []string             `json:"applied_fixers,omitempty"`
+}
+
 func (c *ValidateCommand) Run(args []string) int {
 	ctx, cleanup := handleTermInterrupt(c.Ui)
 	defer cleanup()
@@ -46,6 +116,14 @@ func (c *ValidateCommand) ParseArgs(args []string) (*ValidateArgs, int) {
 		return &cfg, 1
 	}
 	cfg.Path = args[0]
+
+	switch cfg.Format {
+	case "", "text", "json", "sarif":
+	default:
+		c.Ui.Error(fmt.Sprintf("unknown -format value %q: must be one of text, json, sarif", cfg.Format))
+		return &cfg, 1
+	}
+
 	return &cfg, 0
 }
 
@@ -55,8 +133,28 @@ func (c *ValidateCommand) RunContext(ctx context.Context, cla *ValidateArgs) int
 		return ret
 	}
 
+	format := cla.Format
+	if format == "" {
+		format = "text"
+	}
+
+	preDiags := c.evaluatePreConditions(packerStarter, cla.Path)
+	if format == "text" {
+		if ret := writeDiags(c.Ui, nil, preDiags); ret != 0 {
+			return ret
+		}
+	} else if preDiags.HasErrors() {
+		return c.writeValidateResult(format, validateResult{
+			Success:     false,
+			Diagnostics: diagnosticsToValidate(preDiags),
+		})
+	}
+
 	// If we're only checking syntax, then we're done already
 	if cla.SyntaxOnly {
+		if format != "text" {
+			return c.writeValidateResult(format, validateResult{Success: true})
+		}
 		c.Ui.Say("Syntax-only check passed. Everything looks okay.")
 		return 0
 	}
@@ -69,12 +167,37 @@ func (c *ValidateCommand) RunContext(ctx context.Context, cla *ValidateArgs) int
 		Except: cla.Except,
 	})
 
-	// here, something could have gone wrong but we still want to run valid
-	if ret := writeDiags(c.Ui, nil, diags); ret != 0 {
-		return ret
+	if format == "text" {
+		// here, something could have gone wrong but we still want to run valid
+		if ret := writeDiags(c.Ui, nil, diags); ret != 0 {
+			return ret
+		}
+	} else if diags.HasErrors() {
+		return c.writeValidateResult(format, validateResult{
+			Success:     false,
+			Diagnostics: diagnosticsToValidate(diags),
+		})
 	}
 
 	if cfgType, _ := ConfigType(cla.Path); cfgType == "hcl" {
+		hclDiags := diags
+		if cla.CheckReferences {
+			hclDiags = append(append(hcl.Diagnostics{}, diags...), &hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "-check-references is not implemented for HCL templates",
+				Detail:   "reference reachability checks only run for JSON templates today; this template's references were not checked.",
+			})
+		}
+
+		if format != "text" {
+			return c.writeValidateResult(format, validateResult{
+				Success:     !hclDiags.HasErrors(),
+				Diagnostics: diagnosticsToValidate(hclDiags),
+			})
+		}
+		if cla.CheckReferences {
+			c.Ui.Say("[warning] -check-references is not implemented for HCL templates; no references were checked.")
+		}
 		c.Ui.Say("Template validated successfully.")
 		return 0
 	}
@@ -103,28 +226,34 @@ func (c *ValidateCommand) RunContext(ctx context.Context, cla *ValidateArgs) int
 		input[strings.ToLower(k)] = v
 	}
 
-	// fix rawTemplateData into input
+	// fix rawTemplateData into input one fixer at a time, diffing between
+	// steps so each change can be attributed to the fixer that introduced
+	// it. Empty top-level keys are cleaned up after every step, not just
+	// once at the end, so a key that a fixer leaves empty at the end of its
+	// step doesn't show up as a phantom diff. A key that one fixer fills
+	// and a later fixer empties is reported as two diffs, one per fixer.
+	var fixerDiffs []validateFixDiff
+	appliedFixers := make([]string, 0)
 	for _, name := range fix.FixerOrder {
 		var err error
 		fixer, ok := fix.Fixers[name]
 		if !ok {
 			panic("fixer not found: " + name)
 		}
+		before := jsonRoundTrip(input)
 		input, err = fixer.Fix(input)
 		if err != nil {
 			c.Ui.Error(fmt.Sprintf("Error checking against fixers: %s", err))
 			return 1
 		}
-	}
-	// delete empty top-level keys since the fixers seem to add them
-	// willy-nilly
-	for k := range input {
-		ml, ok := input[k].([]map[string]interface{})
-		if !ok {
-			continue
-		}
-		if len(ml) == 0 {
-			delete(input, k)
+		cleanEmptyTopLevelKeys(input)
+		after := jsonRoundTrip(input)
+		if stepDiffs := jsonPointerDiff(before, after); len(stepDiffs) > 0 {
+			for i := range stepDiffs {
+				stepDiffs[i].Fixer = name
+			}
+			fixerDiffs = append(fixerDiffs, stepDiffs...)
+			appliedFixers = append(appliedFixers, name)
 		}
 	}
 	// marshal/unmarshal to make comparable to templateData
@@ -133,6 +262,36 @@ func (c *ValidateCommand) RunContext(ctx context.Context, cla *ValidateArgs) int
 	j, _ := json.Marshal(input)
 	json.Unmarshal(j, &fixedData)
 
+	if cla.Fix {
+		if err := writeFixedTemplate(cla.Path, mergeFixedTemplate(rawTemplateData, fixedData)); err != nil {
+			c.Ui.Error(fmt.Sprintf("failed to write fixed template to %s: %s", cla.Path, err))
+			return 1
+		}
+	}
+
+	var refDiags []validateDiagnostic
+	refErrors := false
+	if cla.CheckReferences {
+		refDiags = checkReferences(ctx, templateData, cla.Path, cla.StrictReferences)
+		for _, d := range refDiags {
+			if d.Severity == "error" {
+				refErrors = true
+			}
+		}
+	}
+
+	if format != "text" {
+		result := validateResult{
+			Success:      len(errs) == 0 && !refErrors,
+			Diagnostics:  append(diagnosticsToValidate(diags), refDiags...),
+			FixableDiffs: fixerDiffs,
+		}
+		if cla.Fix {
+			result.AppliedFixers = appliedFixers
+		}
+		return c.writeValidateResult(format, result)
+	}
+
 	if diff := cmp.Diff(templateData, fixedData); diff != "" {
 		c.Ui.Say("[warning] Fixable configuration found.")
 		c.Ui.Say("You may need to run `packer fix` to get your build to run")
@@ -140,6 +299,29 @@ func (c *ValidateCommand) RunContext(ctx context.Context, cla *ValidateArgs) int
 		log.Printf("Fixable config differences:\n%s", diff)
 	}
 
+	if cla.Fix {
+		if len(appliedFixers) == 0 {
+			c.Ui.Say("No fixers were applicable; the template was left unchanged.\n")
+		} else {
+			c.Ui.Say(fmt.Sprintf("Applied fixers: %s\n", strings.Join(appliedFixers, ", ")))
+		}
+	}
+
+	if len(refDiags) > 0 {
+		c.Ui.Say("Reference check results:")
+		for _, d := range refDiags {
+			prefix := "[warning]"
+			if d.Severity == "error" {
+				prefix = "[error]"
+			}
+			c.Ui.Say(fmt.Sprintf("%s %s: %s", prefix, d.Summary, d.Detail))
+		}
+		c.Ui.Say("")
+	}
+	if refErrors {
+		errs = append(errs, fmt.Errorf("one or more external references failed validation (see above); drop -strict-references to treat these as warnings"))
+	}
+
 	if len(errs) > 0 {
 		c.Ui.Error("Template validation failed. Errors are shown below.\n")
 		for i, err := range errs {
@@ -171,6 +353,332 @@ func (c *ValidateCommand) RunContext(ctx context.Context, cla *ValidateArgs) int
 	return 0
 }
 
+// writeValidateResult renders result to stdout in the requested format and
+// returns the command's exit code.
+func (c *ValidateCommand) writeValidateResult(format string, result validateResult) int {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	switch format {
+	case "json":
+		if err := enc.Encode(result); err != nil {
+			c.Ui.Error(fmt.Sprintf("failed to encode validation result: %s", err))
+			return 1
+		}
+	case "sarif":
+		if err := enc.Encode(toSarif(result)); err != nil {
+			c.Ui.Error(fmt.Sprintf("failed to encode validation result: %s", err))
+			return 1
+		}
+	default:
+		c.Ui.Error(fmt.Sprintf("unknown -format value %q: must be one of text, json, sarif", format))
+		return 1
+	}
+
+	if !result.Success {
+		return 1
+	}
+	return 0
+}
+
+// diagnosticsToValidate converts hcl diagnostics into the stable
+// validateDiagnostic shape used by the json and sarif formats.
+func diagnosticsToValidate(diags hcl.Diagnostics) []validateDiagnostic {
+	out := make([]validateDiagnostic, 0, len(diags))
+	for _, d := range diags {
+		vd := validateDiagnostic{
+			Severity: severityString(d.Severity),
+			Summary:  d.Summary,
+			Detail:   d.Detail,
+		}
+		if d.Subject != nil {
+			vd.File = d.Subject.Filename
+			vd.Line = d.Subject.Start.Line
+			vd.Column = d.Subject.Start.Column
+		}
+		vd.Source = diagnosticSource(d)
+		out = append(out, vd)
+	}
+	return out
+}
+
+// diagnosticSource extracts a builder/provisioner label from a
+// diagnostic's expression, when it has one. It only recognizes the
+// "source.TYPE.NAME" and "build.*" traversal roots HCL2 templates use to
+// reference a builder or a build block, and returns "" otherwise.
+func diagnosticSource(d *hcl.Diagnostic) string {
+	if d.Expression == nil {
+		return ""
+	}
+	for _, t := range d.Expression.Variables() {
+		if len(t) == 0 {
+			continue
+		}
+		root, ok := t[0].(hcl.TraverseRoot)
+		if !ok || (root.Name != "source" && root.Name != "build") {
+			continue
+		}
+		return traversalString(t)
+	}
+	return ""
+}
+
+func traversalString(t hcl.Traversal) string {
+	parts := make([]string, 0, len(t))
+	for _, step := range t {
+		switch s := step.(type) {
+		case hcl.TraverseRoot:
+			parts = append(parts, s.Name)
+		case hcl.TraverseAttr:
+			parts = append(parts, s.Name)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func severityString(s hcl.DiagnosticSeverity) string {
+	switch s {
+	case hcl.DiagError:
+		return "error"
+	case hcl.DiagWarning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// jsonPointerDiff walks before and after and reports every value that
+// differs between them as a JSON Pointer path (RFC 6901). Maps and slices
+// present on both sides are walked down to their leaves; a value present
+// on one side only, or whose type changes, is reported whole at its path.
+func jsonPointerDiff(before, after map[string]interface{}) []validateFixDiff {
+	var out []validateFixDiff
+	walkJSONPointerDiff("", before, after, &out)
+	return out
+}
+
+func walkJSONPointerDiff(prefix string, before, after interface{}, out *[]validateFixDiff) {
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if beforeIsMap && afterIsMap {
+		keys := make(map[string]bool)
+		for k := range beforeMap {
+			keys[k] = true
+		}
+		for k := range afterMap {
+			keys[k] = true
+		}
+		for k := range keys {
+			walkJSONPointerDiff(prefix+"/"+jsonPointerEscape(k), beforeMap[k], afterMap[k], out)
+		}
+		return
+	}
+
+	beforeSlice, beforeIsSlice := before.([]interface{})
+	afterSlice, afterIsSlice := after.([]interface{})
+	if beforeIsSlice && afterIsSlice {
+		max := len(beforeSlice)
+		if len(afterSlice) > max {
+			max = len(afterSlice)
+		}
+		for i := 0; i < max; i++ {
+			var b, a interface{}
+			if i < len(beforeSlice) {
+				b = beforeSlice[i]
+			}
+			if i < len(afterSlice) {
+				a = afterSlice[i]
+			}
+			walkJSONPointerDiff(fmt.Sprintf("%s/%d", prefix, i), b, a, out)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*out = append(*out, validateFixDiff{
+			Path:   prefix,
+			Before: before,
+			After:  after,
+		})
+	}
+}
+
+// cleanEmptyTopLevelKeys deletes empty top-level keys since the fixers
+// seem to add them willy-nilly.
+func cleanEmptyTopLevelKeys(input map[string]interface{}) {
+	for k := range input {
+		ml, ok := input[k].([]map[string]interface{})
+		if !ok {
+			continue
+		}
+		if len(ml) == 0 {
+			delete(input, k)
+		}
+	}
+}
+
+// mergeFixedTemplate overlays fixedData onto a copy of rawTemplateData so
+// -fix's output preserves the original file's keys and casing verbatim
+// for anything the fixer pipeline didn't actually touch. This matters
+// because templateData/input drop top-level keys whose raw value is an
+// empty array before fixers ever run, and cleanEmptyTopLevelKeys removes
+// any that become empty along the way - neither of which fixedData can
+// tell apart from "the fixers removed this key on purpose". Starting from
+// rawTemplateData and only overwriting keys the fixers produced a value
+// for keeps those untouched keys - including intentionally-empty ones -
+// out of the blast radius of a -fix write.
+func mergeFixedTemplate(rawTemplateData, fixedData map[string]interface{}) map[string]interface{} {
+	consumed := make(map[string]bool, len(fixedData))
+	merged := make(map[string]interface{}, len(rawTemplateData)+len(fixedData))
+
+	for k, v := range rawTemplateData {
+		lower := strings.ToLower(k)
+		if fv, ok := fixedData[lower]; ok {
+			merged[k] = fv
+			consumed[lower] = true
+			continue
+		}
+		merged[k] = v
+	}
+
+	for k, v := range fixedData {
+		if consumed[k] {
+			continue
+		}
+		if _, exists := merged[k]; exists {
+			continue
+		}
+		merged[k] = v
+	}
+
+	return merged
+}
+
+// jsonRoundTrip normalizes a map through JSON marshal/unmarshal so two
+// maps produced at different points in the fixer pipeline are comparable
+// with reflect.DeepEqual.
+func jsonRoundTrip(m map[string]interface{}) map[string]interface{} {
+	j, _ := json.Marshal(m)
+	var out map[string]interface{}
+	json.Unmarshal(j, &out)
+	return out
+}
+
+// writeFixedTemplate rewrites the JSON template at path with fixedData,
+// the same serialization `packer fix` would write.
+func writeFixedTemplate(path string, fixedData map[string]interface{}) error {
+	j, err := json.MarshalIndent(fixedData, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, j, 0644)
+}
+
+func jsonPointerEscape(s string) string {
+	s = strings.ReplaceAll(s, "~", "~0")
+	s = strings.ReplaceAll(s, "/", "~1")
+	return s
+}
+
+// sarif types are a minimal subset of the SARIF 2.1.0 schema, just enough
+// to surface packer validate diagnostics in code-scanning UIs.
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine,omitempty"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+func toSarif(result validateResult) sarifLog {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:           "packer",
+				InformationURI: "https://www.packer.io",
+			},
+		},
+		Results: make([]sarifResult, 0, len(result.Diagnostics)),
+	}
+
+	for _, d := range result.Diagnostics {
+		level := "warning"
+		switch d.Severity {
+		case "error":
+			level = "error"
+		case "info":
+			level = "note"
+		}
+
+		sr := sarifResult{
+			RuleID:  "packer-validate",
+			Level:   level,
+			Message: sarifMessage{Text: d.Summary},
+		}
+		if d.File != "" {
+			sr.Locations = []sarifLocation{
+				{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: d.File},
+						Region: sarifRegion{
+							StartLine:   d.Line,
+							StartColumn: d.Column,
+						},
+					},
+				},
+			}
+		}
+		run.Results = append(run.Results, sr)
+	}
+
+	return sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json",
+		Runs:    []sarifRun{run},
+	}
+}
+
 func (*ValidateCommand) Help() string {
 	helpText := `
 Usage: packer validate [options] TEMPLATE
@@ -189,6 +697,18 @@ Options:
   -only=foo,bar,baz      Validate only these builds.
   -var 'key=value'       Variable for templates, can be used multiple times.
   -var-file=path         JSON file containing user variables. [ Note that even in HCL mode this expects file to contain JSON, a fix is comming soon ]
+  -format=text           Output format: text, json, or sarif. Defaults to text.
+  -check-references      Verify that external references (iso_url, checksum_url,
+                         provisioner scripts, http_directory) resolve.
+  -strict-references     Treat unreachable external references as errors.
+                         Only takes effect with -check-references.
+  -fix                   Rewrite the JSON template in place with the output
+                         of the fixers, same as running "packer fix".
+
+  A JSON template's top-level "pre_conditions" array, if present, is
+  evaluated as part of this command. NOTE: "packer build" does not gate
+  on pre_conditions yet - that wiring is still outstanding follow-up work,
+  not something this command can substitute for.
 `
 
 	return strings.TrimSpace(helpText)
@@ -204,10 +724,14 @@ func (*ValidateCommand) AutocompleteArgs() complete.Predictor {
 
 func (*ValidateCommand) AutocompleteFlags() complete.Flags {
 	return complete.Flags{
-		"-syntax-only": complete.PredictNothing,
-		"-except":      complete.PredictNothing,
-		"-only":        complete.PredictNothing,
-		"-var":         complete.PredictNothing,
-		"-var-file":    complete.PredictNothing,
+		"-syntax-only":       complete.PredictNothing,
+		"-except":            complete.PredictNothing,
+		"-only":              complete.PredictNothing,
+		"-var":               complete.PredictNothing,
+		"-var-file":          complete.PredictNothing,
+		"-format":            complete.PredictSet("text", "json", "sarif"),
+		"-check-references":  complete.PredictNothing,
+		"-strict-references": complete.PredictNothing,
+		"-fix":               complete.PredictNothing,
 	}
 }
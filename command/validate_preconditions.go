@@ -0,0 +1,55 @@
+package command
+
+import (
+	"encoding/json"
+
+	"github.com/hashicorp/hcl/v2"
+
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/preconditions"
+)
+
+// evaluatePreConditions runs a template's pre_conditions block, if it has
+// one, and returns the resulting diagnostics. A JSON template with no
+// pre_conditions key yields no diagnostics.
+//
+// For JSON templates the "pre_conditions" array is parsed and each
+// expression evaluated, against an eval context exposing the template's
+// own variables, packer_version and env(), before GetBuilds runs. HCL
+// templates are not evaluated: hcl2template's PackerConfig doesn't parse a
+// pre_conditions block, so every HCL template gets one warning saying so,
+// whether or not it has such a block.
+//
+// `packer build` does not call this; ValidateCommand.Help in validate.go
+// says so.
+func (c *ValidateCommand) evaluatePreConditions(packerStarter packer.Starter, path string) hcl.Diagnostics {
+	if cfgType, _ := ConfigType(path); cfgType == "hcl" {
+		return hcl.Diagnostics{
+			{
+				Severity: hcl.DiagWarning,
+				Summary:  "pre_conditions are not evaluated for HCL templates",
+				Detail:   "pre_conditions support for HCL templates is not implemented yet; any pre_conditions block in this template was not checked.",
+			},
+		}
+	}
+
+	core, ok := packerStarter.(*packer.Core)
+	if !ok {
+		return nil
+	}
+
+	var rawTemplateData map[string]interface{}
+	if err := json.Unmarshal(core.Template.RawContents, &rawTemplateData); err != nil {
+		return nil
+	}
+
+	return evaluateJSONPreConditions(rawTemplateData)
+}
+
+// evaluateJSONPreConditions parses and evaluates the pre_conditions array
+// of a JSON template, if it has one. It's a thin wrapper around
+// preconditions.EvaluateJSON so the evaluation logic lives in one place
+// shared with any future caller outside of validate.
+func evaluateJSONPreConditions(rawTemplateData map[string]interface{}) hcl.Diagnostics {
+	return preconditions.EvaluateJSON(rawTemplateData)
+}
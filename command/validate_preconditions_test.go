@@ -0,0 +1,63 @@
+package command
+
+import "testing"
+
+func TestEvaluateJSONPreConditions_noKey(t *testing.T) {
+	diags := evaluateJSONPreConditions(map[string]interface{}{"builders": []interface{}{}})
+	if diags.HasErrors() {
+		t.Fatalf("expected no diagnostics when pre_conditions is absent, got %s", diags)
+	}
+}
+
+func TestEvaluateJSONPreConditions_passing(t *testing.T) {
+	rawTemplateData := map[string]interface{}{
+		"pre_conditions": []interface{}{
+			map[string]interface{}{"expression": "1 == 1", "message": "always true"},
+		},
+	}
+
+	if diags := evaluateJSONPreConditions(rawTemplateData); diags.HasErrors() {
+		t.Fatalf("expected no diagnostics for a passing condition, got %s", diags)
+	}
+}
+
+func TestEvaluateJSONPreConditions_failing(t *testing.T) {
+	rawTemplateData := map[string]interface{}{
+		"pre_conditions": []interface{}{
+			map[string]interface{}{"expression": "1 == 2", "message": "one is not two"},
+		},
+	}
+
+	diags := evaluateJSONPreConditions(rawTemplateData)
+	if !diags.HasErrors() {
+		t.Fatal("expected a diagnostic for a failing condition")
+	}
+	if diags[0].Detail != "one is not two" {
+		t.Fatalf("expected the condition's message in the diagnostic, got %q", diags[0].Detail)
+	}
+}
+
+func TestEvaluateJSONPreConditions_malformed(t *testing.T) {
+	rawTemplateData := map[string]interface{}{
+		"pre_conditions": "not an array",
+	}
+
+	if diags := evaluateJSONPreConditions(rawTemplateData); !diags.HasErrors() {
+		t.Fatal("expected a diagnostic for a malformed pre_conditions value")
+	}
+}
+
+func TestValidateCommand_evaluatePreConditions_hclWarns(t *testing.T) {
+	c := &ValidateCommand{}
+
+	diags := c.evaluatePreConditions(nil, "template.pkr.hcl")
+	if diags.HasErrors() {
+		t.Fatalf("an unwired HCL template should warn, not error, got %s", diags)
+	}
+	if len(diags) != 1 {
+		t.Fatalf("expected exactly one diagnostic, got %d", len(diags))
+	}
+	if diags[0].Summary != "pre_conditions are not evaluated for HCL templates" {
+		t.Fatalf("unexpected diagnostic summary: %q", diags[0].Summary)
+	}
+}
@@ -0,0 +1,111 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/reference"
+)
+
+// referenceRefs extracts the external references (iso_url, iso_checksum_url,
+// http_directory, provisioner scripts) from a parsed JSON template so they
+// can be checked for reachability by -check-references.
+func referenceRefs(templateData map[string]interface{}) []reference.Ref {
+	var refs []reference.Ref
+
+	for _, raw := range asMapSlice(templateData["builders"]) {
+		name := refSourceName(raw)
+		refs = append(refs, extractValueRef(raw, "iso_url", reference.KindISOURL, name)...)
+		refs = append(refs, extractValueRef(raw, "iso_checksum_url", reference.KindChecksumURL, name)...)
+		refs = append(refs, extractValueRef(raw, "http_directory", reference.KindDirectory, name)...)
+	}
+
+	for _, raw := range asMapSlice(templateData["provisioners"]) {
+		name := refSourceName(raw)
+		refs = append(refs, extractValueRef(raw, "script", reference.KindFile, name)...)
+		refs = append(refs, extractValueRef(raw, "source", reference.KindFile, name)...)
+		refs = append(refs, extractSliceRef(raw, "scripts", reference.KindFile, name)...)
+	}
+
+	return refs
+}
+
+func refSourceName(raw map[string]interface{}) string {
+	if t, ok := raw["type"].(string); ok {
+		return t
+	}
+	return "unknown"
+}
+
+func extractValueRef(raw map[string]interface{}, field string, kind reference.Kind, source string) []reference.Ref {
+	v, ok := raw[field].(string)
+	if !ok || v == "" {
+		return nil
+	}
+	return []reference.Ref{{Kind: kind, Value: v, Source: source, Field: field}}
+}
+
+func extractSliceRef(raw map[string]interface{}, field string, kind reference.Kind, source string) []reference.Ref {
+	vals, ok := raw[field].([]interface{})
+	if !ok {
+		return nil
+	}
+	refs := make([]reference.Ref, 0, len(vals))
+	for _, v := range vals {
+		s, ok := v.(string)
+		if !ok || s == "" {
+			continue
+		}
+		refs = append(refs, reference.Ref{Kind: kind, Value: s, Source: source, Field: field})
+	}
+	return refs
+}
+
+func asMapSlice(v interface{}) []map[string]interface{} {
+	vals, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	out := make([]map[string]interface{}, 0, len(vals))
+	for _, v := range vals {
+		if m, ok := v.(map[string]interface{}); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// checkReferences walks templateData for external references and checks
+// that each one resolves, returning one diagnostic per broken reference.
+// Results are warnings unless strict is true, in which case they are
+// errors.
+//
+// Only JSON templates are supported today; HCL templates need their own
+// walker over hcl2template's parsed bodies, which is left for follow-up
+// work.
+func checkReferences(ctx context.Context, templateData map[string]interface{}, templatePath string, strict bool) []validateDiagnostic {
+	baseDir := filepath.Dir(templatePath)
+	walker := reference.NewWalker(
+		reference.FileChecker{BaseDir: baseDir},
+		reference.DirectoryChecker{BaseDir: baseDir},
+	)
+
+	severity := "warning"
+	if strict {
+		severity = "error"
+	}
+
+	var diags []validateDiagnostic
+	for _, res := range walker.Check(ctx, referenceRefs(templateData)) {
+		if res.OK() {
+			continue
+		}
+		diags = append(diags, validateDiagnostic{
+			Severity: severity,
+			Summary:  fmt.Sprintf("unreachable %s reference in %s", res.Ref.Kind, res.Ref.Source),
+			Detail:   fmt.Sprintf("%s %q: %s", res.Ref.Field, res.Ref.Value, res.Err),
+		})
+	}
+	return diags
+}
@@ -0,0 +1,312 @@
+package command
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	old := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+
+	fn()
+
+	w.Close()
+	os.Stdout = old
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestJSONPointerDiff(t *testing.T) {
+	before := map[string]interface{}{
+		"name": "old",
+		"provisioners": []interface{}{
+			map[string]interface{}{"script": "a.sh"},
+		},
+	}
+	after := map[string]interface{}{
+		"name": "new",
+		"provisioners": []interface{}{
+			map[string]interface{}{"script": "b.sh"},
+		},
+	}
+
+	diffs := jsonPointerDiff(before, after)
+	wantPaths := map[string]bool{
+		"/name":                  true,
+		"/provisioners/0/script": true,
+	}
+	if len(diffs) != len(wantPaths) {
+		t.Fatalf("expected %d diffs, got %d: %#v", len(wantPaths), len(diffs), diffs)
+	}
+	for _, d := range diffs {
+		if !wantPaths[d.Path] {
+			t.Errorf("unexpected diff path %q", d.Path)
+		}
+	}
+}
+
+func TestJSONPointerDiff_noChanges(t *testing.T) {
+	data := map[string]interface{}{"name": "same"}
+	if diffs := jsonPointerDiff(data, data); len(diffs) != 0 {
+		t.Fatalf("expected no diffs for identical maps, got %#v", diffs)
+	}
+}
+
+func TestJSONPointerEscape(t *testing.T) {
+	if got := jsonPointerEscape("a/b~c"); got != "a~1b~0c" {
+		t.Fatalf("unexpected escape result: %q", got)
+	}
+}
+
+func TestDiagnosticsToValidate(t *testing.T) {
+	diags := hcl.Diagnostics{
+		{
+			Severity: hcl.DiagError,
+			Summary:  "bad config",
+			Detail:   "detail here",
+			Subject: &hcl.Range{
+				Filename: "main.pkr.hcl",
+				Start:    hcl.Pos{Line: 4, Column: 2},
+			},
+		},
+	}
+
+	out := diagnosticsToValidate(diags)
+	if len(out) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d", len(out))
+	}
+	vd := out[0]
+	if vd.Severity != "error" || vd.File != "main.pkr.hcl" || vd.Line != 4 || vd.Column != 2 {
+		t.Fatalf("unexpected diagnostic: %#v", vd)
+	}
+}
+
+func TestDiagnosticSource(t *testing.T) {
+	expr, diags := hclsyntax.ParseExpression([]byte("source.amazon-ebs.example"), "test.pkr.hcl", hcl.InitialPos)
+	if diags.HasErrors() {
+		t.Fatalf("failed to parse test expression: %s", diags)
+	}
+
+	d := &hcl.Diagnostic{Severity: hcl.DiagError, Summary: "broken", Expression: expr}
+	if got := diagnosticSource(d); got != "source.amazon-ebs.example" {
+		t.Fatalf("expected source.amazon-ebs.example, got %q", got)
+	}
+}
+
+func TestDiagnosticSource_noExpression(t *testing.T) {
+	d := &hcl.Diagnostic{Severity: hcl.DiagError, Summary: "broken"}
+	if got := diagnosticSource(d); got != "" {
+		t.Fatalf("expected empty source, got %q", got)
+	}
+}
+
+func TestToSarif(t *testing.T) {
+	result := validateResult{
+		Success: false,
+		Diagnostics: []validateDiagnostic{
+			{Severity: "error", Summary: "broken", File: "a.pkr.hcl", Line: 3, Column: 5},
+			{Severity: "warning", Summary: "maybe fine"},
+		},
+	}
+
+	log := toSarif(result)
+	if len(log.Runs) != 1 || len(log.Runs[0].Results) != 2 {
+		t.Fatalf("expected one run with two results, got %#v", log)
+	}
+	if log.Runs[0].Results[0].Level != "error" {
+		t.Fatalf("expected error level, got %s", log.Runs[0].Results[0].Level)
+	}
+	if len(log.Runs[0].Results[0].Locations) != 1 {
+		t.Fatalf("expected a location for a diagnostic with a file, got %#v", log.Runs[0].Results[0].Locations)
+	}
+	if len(log.Runs[0].Results[1].Locations) != 0 {
+		t.Fatalf("expected no location for a diagnostic without a file, got %#v", log.Runs[0].Results[1].Locations)
+	}
+}
+
+func TestWriteValidateResult_json(t *testing.T) {
+	c := &ValidateCommand{}
+	result := validateResult{
+		Success:     true,
+		Diagnostics: []validateDiagnostic{{Severity: "warning", Summary: "example"}},
+	}
+
+	var code int
+	out := captureStdout(t, func() {
+		code = c.writeValidateResult("json", result)
+	})
+
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+
+	var decoded validateResult
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatalf("expected valid json output, got error %s: %s", err, out)
+	}
+	if len(decoded.Diagnostics) != 1 || decoded.Diagnostics[0].Summary != "example" {
+		t.Fatalf("unexpected diagnostics in output: %#v", decoded.Diagnostics)
+	}
+}
+
+func TestWriteValidateResult_failureExitCode(t *testing.T) {
+	c := &ValidateCommand{}
+
+	var code int
+	captureStdout(t, func() {
+		code = c.writeValidateResult("json", validateResult{Success: false})
+	})
+	if code != 1 {
+		t.Fatalf("expected exit code 1 for a failed result, got %d", code)
+	}
+}
+
+func TestWriteValidateResult_sarif(t *testing.T) {
+	c := &ValidateCommand{}
+	result := validateResult{
+		Success: true,
+		Diagnostics: []validateDiagnostic{
+			{Severity: "error", Summary: "broken", File: "a.pkr.hcl", Line: 3, Column: 5},
+		},
+	}
+
+	var code int
+	out := captureStdout(t, func() {
+		code = c.writeValidateResult("sarif", result)
+	})
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal([]byte(out), &log); err != nil {
+		t.Fatalf("expected valid sarif json, got error %s: %s", err, out)
+	}
+	if len(log.Runs) != 1 || len(log.Runs[0].Results) != 1 {
+		t.Fatalf("expected one run with one result, got %#v", log)
+	}
+}
+
+func TestCleanEmptyTopLevelKeys(t *testing.T) {
+	input := map[string]interface{}{
+		"builders": []map[string]interface{}{{"type": "amazon-ebs"}},
+		"empty":    []map[string]interface{}{},
+		"other":    "value",
+	}
+
+	cleanEmptyTopLevelKeys(input)
+
+	if _, ok := input["empty"]; ok {
+		t.Fatal("expected the empty top-level key to be removed")
+	}
+	if _, ok := input["builders"]; !ok {
+		t.Fatal("expected the non-empty top-level key to survive")
+	}
+	if _, ok := input["other"]; !ok {
+		t.Fatal("expected non-slice top-level keys to survive untouched")
+	}
+}
+
+func TestCleanEmptyTopLevelKeys_hidesPhantomFixerDiffs(t *testing.T) {
+	// Regression test: a fixer step that both adds and empties a
+	// top-level key in the same step must not show up as a diff, since
+	// it never appears in the fixedData actually written to disk.
+	before := jsonRoundTrip(map[string]interface{}{"name": "example"})
+
+	afterFixer := map[string]interface{}{
+		"name":         "example",
+		"provisioners": []map[string]interface{}{},
+	}
+	cleanEmptyTopLevelKeys(afterFixer)
+	after := jsonRoundTrip(afterFixer)
+
+	if diffs := jsonPointerDiff(before, after); len(diffs) != 0 {
+		t.Fatalf("expected no diffs once the empty top-level key is cleaned, got %#v", diffs)
+	}
+}
+
+func TestMergeFixedTemplate_preservesUntouchedEmptyArrayKey(t *testing.T) {
+	rawTemplateData := map[string]interface{}{
+		"Builders":        []interface{}{map[string]interface{}{"type": "amazon-ebs"}},
+		"Post-Processors": []interface{}{},
+	}
+	fixedData := map[string]interface{}{
+		"builders": []interface{}{map[string]interface{}{"type": "amazon-ebs", "fixed": true}},
+	}
+
+	merged := mergeFixedTemplate(rawTemplateData, fixedData)
+
+	if _, ok := merged["Post-Processors"]; !ok {
+		t.Fatal("expected an untouched empty top-level array key to survive the merge")
+	}
+	builders, ok := merged["Builders"].([]interface{})
+	if !ok || len(builders) != 1 {
+		t.Fatalf("expected the fixer's output for Builders to be used, got %#v", merged["Builders"])
+	}
+	if b, ok := builders[0].(map[string]interface{}); !ok || b["fixed"] != true {
+		t.Fatalf("expected the fixed builder value, got %#v", builders[0])
+	}
+}
+
+func TestMergeFixedTemplate_addsNewTopLevelKeys(t *testing.T) {
+	rawTemplateData := map[string]interface{}{"name": "example"}
+	fixedData := map[string]interface{}{
+		"name":         "example",
+		"provisioners": []interface{}{map[string]interface{}{"type": "shell"}},
+	}
+
+	merged := mergeFixedTemplate(rawTemplateData, fixedData)
+
+	if _, ok := merged["provisioners"]; !ok {
+		t.Fatal("expected a top-level key introduced by a fixer to survive the merge")
+	}
+}
+
+func TestWriteFixedTemplate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "template.json")
+	if err := os.WriteFile(path, []byte(`{"old":"data"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fixed := map[string]interface{}{
+		"builders": []interface{}{
+			map[string]interface{}{"type": "amazon-ebs"},
+		},
+	}
+	if err := writeFixedTemplate(path, fixed); err != nil {
+		t.Fatalf("writeFixedTemplate failed: %s", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("expected valid json, got error %s: %s", err, data)
+	}
+	if _, ok := got["old"]; ok {
+		t.Fatal("expected the fixed template to replace the file contents, not merge with them")
+	}
+	if _, ok := got["builders"]; !ok {
+		t.Fatal("expected the fixed template's builders key to be written")
+	}
+}
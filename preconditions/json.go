@@ -0,0 +1,104 @@
+package preconditions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+
+	"github.com/hashicorp/packer/version"
+)
+
+// jsonCondition is the on-disk shape of one entry in a JSON template's
+// top-level "pre_conditions" array: an HCL expression and the message to
+// report when it evaluates to false.
+type jsonCondition struct {
+	Expression string `json:"expression"`
+	Message    string `json:"message"`
+}
+
+// EvaluateJSON parses and evaluates the pre_conditions array of a JSON
+// template, if it has one, against an eval context exposing the
+// template's own top-level variables, packer_version, and an env()
+// function. It is exported so both `packer validate` and, eventually,
+// `packer build` can gate on the same pre_conditions without duplicating
+// the parsing/eval-context logic.
+//
+// A template with no pre_conditions key yields no diagnostics.
+func EvaluateJSON(rawTemplateData map[string]interface{}) hcl.Diagnostics {
+	raw, ok := rawTemplateData["pre_conditions"]
+	if !ok {
+		return nil
+	}
+
+	var entries []jsonCondition
+	j, err := json.Marshal(raw)
+	if err == nil {
+		err = json.Unmarshal(j, &entries)
+	}
+	if err != nil {
+		return hcl.Diagnostics{
+			{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid pre_conditions",
+				Detail:   fmt.Sprintf("pre_conditions must be an array of {expression, message} objects: %s", err),
+			},
+		}
+	}
+
+	evaluator := &Evaluator{}
+	for _, entry := range entries {
+		expr, diags := hclsyntax.ParseExpression([]byte(entry.Expression), "pre_conditions", hcl.InitialPos)
+		if diags.HasErrors() {
+			return diags
+		}
+		evaluator.Conditions = append(evaluator.Conditions, Condition{
+			Expr:    expr,
+			Message: entry.Message,
+			Range:   expr.Range(),
+		})
+	}
+
+	return evaluator.Evaluate(jsonEvalContext(rawTemplateData))
+}
+
+// jsonEvalContext builds the eval context pre_conditions are checked
+// against for a JSON template: its own top-level "variables" (JSON
+// template variables are always strings), packer_version, and an env()
+// function. Variables scoped to a single builder or provisioner block
+// aren't exposed here - pre_conditions run once, before GetBuilds, against
+// the raw template as a whole.
+func jsonEvalContext(rawTemplateData map[string]interface{}) *hcl.EvalContext {
+	vars := map[string]cty.Value{
+		"packer_version": cty.StringVal(version.Version),
+	}
+
+	if rawVars, ok := rawTemplateData["variables"].(map[string]interface{}); ok {
+		for k, v := range rawVars {
+			if s, ok := v.(string); ok {
+				vars[k] = cty.StringVal(s)
+			}
+		}
+	}
+
+	return &hcl.EvalContext{
+		Variables: vars,
+		Functions: map[string]function.Function{
+			"env": envFunc,
+		},
+	}
+}
+
+var envFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "key", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		return cty.StringVal(os.Getenv(args[0].AsString())), nil
+	},
+})
@@ -0,0 +1,73 @@
+package preconditions
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEvaluateJSON_envFunction(t *testing.T) {
+	os.Setenv("PRECONDITIONS_TEST_VAR", "set")
+	defer os.Unsetenv("PRECONDITIONS_TEST_VAR")
+
+	rawTemplateData := map[string]interface{}{
+		"pre_conditions": []interface{}{
+			map[string]interface{}{
+				"expression": `env("PRECONDITIONS_TEST_VAR") != ""`,
+				"message":    "PRECONDITIONS_TEST_VAR must be set",
+			},
+		},
+	}
+
+	if diags := EvaluateJSON(rawTemplateData); diags.HasErrors() {
+		t.Fatalf("expected env() to resolve the set variable, got %s", diags)
+	}
+}
+
+func TestEvaluateJSON_envFunction_unset(t *testing.T) {
+	rawTemplateData := map[string]interface{}{
+		"pre_conditions": []interface{}{
+			map[string]interface{}{
+				"expression": `env("PRECONDITIONS_TEST_VAR_UNSET") != ""`,
+				"message":    "PRECONDITIONS_TEST_VAR_UNSET must be set",
+			},
+		},
+	}
+
+	diags := EvaluateJSON(rawTemplateData)
+	if !diags.HasErrors() {
+		t.Fatal("expected a diagnostic when the referenced environment variable is unset")
+	}
+}
+
+func TestEvaluateJSON_templateVariable(t *testing.T) {
+	rawTemplateData := map[string]interface{}{
+		"variables": map[string]interface{}{
+			"source_ami": "ami-1234",
+		},
+		"pre_conditions": []interface{}{
+			map[string]interface{}{"expression": `source_ami != ""`, "message": "source_ami must be set"},
+		},
+	}
+
+	if diags := EvaluateJSON(rawTemplateData); diags.HasErrors() {
+		t.Fatalf("expected the template's own variable to resolve, got %s", diags)
+	}
+}
+
+func TestEvaluateJSON_packerVersion(t *testing.T) {
+	rawTemplateData := map[string]interface{}{
+		"pre_conditions": []interface{}{
+			map[string]interface{}{"expression": `packer_version != ""`, "message": "packer_version must be set"},
+		},
+	}
+
+	if diags := EvaluateJSON(rawTemplateData); diags.HasErrors() {
+		t.Fatalf("expected packer_version to resolve, got %s", diags)
+	}
+}
+
+func TestEvaluateJSON_noKey(t *testing.T) {
+	if diags := EvaluateJSON(map[string]interface{}{"builders": []interface{}{}}); diags.HasErrors() {
+		t.Fatalf("expected no diagnostics when pre_conditions is absent, got %s", diags)
+	}
+}
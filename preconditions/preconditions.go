@@ -0,0 +1,61 @@
+// Package preconditions evaluates a template's pre_conditions block: a set
+// of HCL expressions that must hold before validation or a build is
+// allowed to proceed.
+package preconditions
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Condition is a single pre-condition: an expression that must evaluate
+// to true, and the message to report when it does not.
+type Condition struct {
+	Expr    hcl.Expression
+	Message string
+	Range   hcl.Range
+}
+
+// Evaluator evaluates a set of pre-conditions against an hcl.EvalContext.
+// Callers are responsible for building that context; see EvaluateJSON for
+// the context JSON templates are evaluated with.
+type Evaluator struct {
+	Conditions []Condition
+}
+
+// Evaluate runs every condition and returns one diagnostic per failing or
+// non-bool condition, plus every diagnostic produced while evaluating a
+// condition's expression. An empty Evaluator (no conditions) always
+// succeeds.
+func (e *Evaluator) Evaluate(ctx *hcl.EvalContext) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	for _, cond := range e.Conditions {
+		val, condDiags := cond.Expr.Value(ctx)
+		diags = append(diags, condDiags...)
+		if condDiags.HasErrors() {
+			continue
+		}
+
+		if val.IsNull() || !val.Type().Equals(cty.Bool) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid pre_condition expression",
+				Detail:   "The pre_condition expression must evaluate to a bool.",
+				Subject:  cond.Range.Ptr(),
+			})
+			continue
+		}
+
+		if val.False() {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Pre-condition failed",
+				Detail:   cond.Message,
+				Subject:  cond.Range.Ptr(),
+			})
+		}
+	}
+
+	return diags
+}
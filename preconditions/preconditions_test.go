@@ -0,0 +1,95 @@
+package preconditions
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func mustParseExpr(t *testing.T, src string) hcl.Expression {
+	t.Helper()
+	expr, diags := hclsyntax.ParseExpression([]byte(src), "test.pkr.hcl", hcl.InitialPos)
+	if diags.HasErrors() {
+		t.Fatalf("failed to parse expression %q: %s", src, diags)
+	}
+	return expr
+}
+
+func TestEvaluator_allPass(t *testing.T) {
+	e := &Evaluator{
+		Conditions: []Condition{
+			{Expr: mustParseExpr(t, "1 == 1"), Message: "always true"},
+		},
+	}
+
+	if diags := e.Evaluate(nil); diags.HasErrors() {
+		t.Fatalf("expected no diagnostics, got %s", diags)
+	}
+}
+
+func TestEvaluator_failingCondition(t *testing.T) {
+	e := &Evaluator{
+		Conditions: []Condition{
+			{Expr: mustParseExpr(t, "1 == 2"), Message: "one is not two"},
+		},
+	}
+
+	diags := e.Evaluate(nil)
+	if !diags.HasErrors() {
+		t.Fatal("expected a diagnostic for the failing condition")
+	}
+	if diags[0].Detail != "one is not two" {
+		t.Fatalf("expected the condition's message in the diagnostic, got %q", diags[0].Detail)
+	}
+}
+
+func TestEvaluator_nonBoolExpression(t *testing.T) {
+	e := &Evaluator{
+		Conditions: []Condition{
+			{Expr: mustParseExpr(t, `"not a bool"`), Message: "unused"},
+		},
+	}
+
+	diags := e.Evaluate(nil)
+	if !diags.HasErrors() {
+		t.Fatal("expected a diagnostic for a non-bool condition expression")
+	}
+}
+
+func TestEvaluator_noConditions(t *testing.T) {
+	e := &Evaluator{}
+	if diags := e.Evaluate(nil); diags.HasErrors() {
+		t.Fatalf("expected no diagnostics for an empty evaluator, got %s", diags)
+	}
+}
+
+func TestEvaluator_usesEvalContext(t *testing.T) {
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"source_ami": cty.StringVal("ami-1234"),
+		},
+	}
+	e := &Evaluator{
+		Conditions: []Condition{
+			{Expr: mustParseExpr(t, `source_ami != ""`), Message: "source_ami must be set"},
+		},
+	}
+
+	if diags := e.Evaluate(ctx); diags.HasErrors() {
+		t.Fatalf("expected the condition to resolve against the supplied context, got %s", diags)
+	}
+}
+
+func TestEvaluator_missingVariableWithNilContext(t *testing.T) {
+	e := &Evaluator{
+		Conditions: []Condition{
+			{Expr: mustParseExpr(t, `source_ami != ""`), Message: "source_ami must be set"},
+		},
+	}
+
+	if diags := e.Evaluate(nil); !diags.HasErrors() {
+		t.Fatal("expected a diagnostic when a condition references a variable with no eval context")
+	}
+}
@@ -0,0 +1,132 @@
+package reference
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var defaultHTTPTimeout = 10 * time.Second
+
+// HTTPChecker issues a HEAD request against iso_url references.
+type HTTPChecker struct {
+	Client *http.Client
+}
+
+func (c HTTPChecker) Kind() Kind { return KindISOURL }
+
+func (c HTTPChecker) Check(ctx context.Context, ref Ref) Result {
+	return Result{Ref: ref, Err: headCheck(ctx, c.client(), ref.Value)}
+}
+
+func (c HTTPChecker) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+	return &http.Client{Timeout: defaultHTTPTimeout}
+}
+
+// knownChecksumAlgorithms mirrors the algorithms Packer's own checksum
+// fixer/provisioners accept.
+var knownChecksumAlgorithms = map[string]bool{
+	"md5": true, "sha1": true, "sha256": true, "sha512": true,
+}
+
+// ChecksumURLChecker confirms a checksum_url is of the form
+// "algorithm:url", that the algorithm is recognized, and that the URL it
+// points at resolves.
+type ChecksumURLChecker struct {
+	Client *http.Client
+}
+
+func (c ChecksumURLChecker) Kind() Kind { return KindChecksumURL }
+
+func (c ChecksumURLChecker) Check(ctx context.Context, ref Ref) Result {
+	algo, url, ok := splitChecksumURL(ref.Value)
+	if !ok {
+		return Result{Ref: ref, Err: fmt.Errorf("checksum_url %q must be of the form algorithm:url", ref.Value)}
+	}
+	if !knownChecksumAlgorithms[strings.ToLower(algo)] {
+		return Result{Ref: ref, Err: fmt.Errorf("unrecognized checksum algorithm %q", algo)}
+	}
+	client := c.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	return Result{Ref: ref, Err: headCheck(ctx, client, url)}
+}
+
+func splitChecksumURL(value string) (algo, url string, ok bool) {
+	parts := strings.SplitN(value, ":", 2)
+	// A bare URL such as "http://host/file" splits into its scheme and
+	// "//host/file"; that is a URL with no algorithm prefix.
+	if len(parts) != 2 || strings.HasPrefix(parts[1], "//") {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+func headCheck(ctx context.Context, client *http.Client, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// FileChecker stats file/shell provisioner script, scripts, and source
+// paths, resolving relative paths against BaseDir.
+type FileChecker struct {
+	BaseDir string
+}
+
+func (c FileChecker) Kind() Kind { return KindFile }
+
+func (c FileChecker) Check(ctx context.Context, ref Ref) Result {
+	if _, err := os.Stat(c.resolve(ref.Value)); err != nil {
+		return Result{Ref: ref, Err: err}
+	}
+	return Result{Ref: ref}
+}
+
+func (c FileChecker) resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(c.BaseDir, path)
+}
+
+// DirectoryChecker stats http_directory paths, resolving relative paths
+// against BaseDir.
+type DirectoryChecker struct {
+	BaseDir string
+}
+
+func (c DirectoryChecker) Kind() Kind { return KindDirectory }
+
+func (c DirectoryChecker) Check(ctx context.Context, ref Ref) Result {
+	path := ref.Value
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(c.BaseDir, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return Result{Ref: ref, Err: err}
+	}
+	if !info.IsDir() {
+		return Result{Ref: ref, Err: fmt.Errorf("%s is not a directory", path)}
+	}
+	return Result{Ref: ref}
+}
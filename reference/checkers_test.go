@@ -0,0 +1,135 @@
+package reference
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitChecksumURL(t *testing.T) {
+	cases := []struct {
+		in        string
+		algo, url string
+		ok        bool
+	}{
+		{"sha256:http://example.com/checksum", "sha256", "http://example.com/checksum", true},
+		{"http://example.com/checksum", "", "", false},
+	}
+
+	for _, tc := range cases {
+		algo, url, ok := splitChecksumURL(tc.in)
+		if ok != tc.ok || algo != tc.algo || url != tc.url {
+			t.Errorf("splitChecksumURL(%q) = %q, %q, %v; want %q, %q, %v", tc.in, algo, url, ok, tc.algo, tc.url, tc.ok)
+		}
+	}
+}
+
+func TestChecksumURLChecker_unknownAlgorithm(t *testing.T) {
+	checker := ChecksumURLChecker{}
+	res := checker.Check(context.Background(), Ref{Kind: KindChecksumURL, Value: "rot13:http://example.com/checksum"})
+	if res.OK() {
+		t.Fatal("expected an error for an unrecognized checksum algorithm")
+	}
+}
+
+func TestHTTPChecker_resolves(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("expected a HEAD request, got %s", r.Method)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	checker := HTTPChecker{}
+	if res := checker.Check(context.Background(), Ref{Kind: KindISOURL, Value: srv.URL}); !res.OK() {
+		t.Fatalf("expected a 200 response to resolve: %s", res.Err)
+	}
+}
+
+func TestHTTPChecker_notFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	checker := HTTPChecker{}
+	if res := checker.Check(context.Background(), Ref{Kind: KindISOURL, Value: srv.URL}); res.OK() {
+		t.Fatal("expected a 404 response to fail to resolve")
+	}
+}
+
+func TestHTTPChecker_networkError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := srv.URL
+	srv.Close()
+
+	checker := HTTPChecker{}
+	if res := checker.Check(context.Background(), Ref{Kind: KindISOURL, Value: url}); res.OK() {
+		t.Fatal("expected a closed connection to fail to resolve")
+	}
+}
+
+func TestChecksumURLChecker_resolves(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	checker := ChecksumURLChecker{}
+	if res := checker.Check(context.Background(), Ref{Kind: KindChecksumURL, Value: "sha256:" + srv.URL}); !res.OK() {
+		t.Fatalf("expected a 200 checksum_url to resolve: %s", res.Err)
+	}
+}
+
+func TestChecksumURLChecker_unreachable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	checker := ChecksumURLChecker{}
+	if res := checker.Check(context.Background(), Ref{Kind: KindChecksumURL, Value: "sha256:" + srv.URL}); res.OK() {
+		t.Fatal("expected a 500 checksum_url to fail to resolve")
+	}
+}
+
+func TestFileChecker(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "install.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	checker := FileChecker{BaseDir: dir}
+
+	if res := checker.Check(context.Background(), Ref{Kind: KindFile, Value: "install.sh"}); !res.OK() {
+		t.Fatalf("expected install.sh to resolve: %s", res.Err)
+	}
+	if res := checker.Check(context.Background(), Ref{Kind: KindFile, Value: "missing.sh"}); res.OK() {
+		t.Fatal("expected missing.sh to fail to resolve")
+	}
+}
+
+func TestDirectoryChecker(t *testing.T) {
+	dir := t.TempDir()
+	checker := DirectoryChecker{BaseDir: filepath.Dir(dir)}
+
+	if res := checker.Check(context.Background(), Ref{Kind: KindDirectory, Value: filepath.Base(dir)}); !res.OK() {
+		t.Fatalf("expected %s to resolve as a directory: %s", dir, res.Err)
+	}
+	if res := checker.Check(context.Background(), Ref{Kind: KindDirectory, Value: "does-not-exist"}); res.OK() {
+		t.Fatal("expected a missing directory to fail to resolve")
+	}
+}
+
+func TestWalker_skipsUnregisteredKinds(t *testing.T) {
+	w := &Walker{checkers: make(map[Kind]Checker)}
+	results := w.Check(context.Background(), []Ref{{Kind: "ami_id", Value: "ami-1234"}})
+	if len(results) != 0 {
+		t.Fatalf("expected no results for an unregistered kind, got %d", len(results))
+	}
+}
@@ -0,0 +1,92 @@
+// Package reference implements pluggable checks that verify external
+// references inside a template - ISO URLs, checksum URLs, local files and
+// directories - actually resolve.
+package reference
+
+import "context"
+
+// Kind identifies the category of external reference a Checker inspects.
+type Kind string
+
+const (
+	KindISOURL      Kind = "iso_url"
+	KindChecksumURL Kind = "checksum_url"
+	KindFile        Kind = "file"
+	KindDirectory   Kind = "http_directory"
+)
+
+// Ref is a single external reference extracted from a template.
+type Ref struct {
+	Kind  Kind
+	Value string
+	// Source is the builder or provisioner name the reference came from.
+	Source string
+	// Field is the template field name the value came from, e.g. "script".
+	Field string
+}
+
+// Result is the outcome of checking a single Ref. Err is nil when the
+// reference resolved successfully.
+type Result struct {
+	Ref Ref
+	Err error
+}
+
+// OK reports whether the reference resolved successfully.
+func (r Result) OK() bool {
+	return r.Err == nil
+}
+
+// Checker resolves a Ref of a given Kind and reports whether it is
+// reachable. Builders contribute additional ref kinds (AMI IDs, GCE
+// images, ...) by registering their own Checker with a Walker rather than
+// this package hard-coding every builder's reference shapes.
+type Checker interface {
+	Kind() Kind
+	Check(ctx context.Context, ref Ref) Result
+}
+
+// Walker runs the Checker registered for each ref's Kind against the Refs
+// it is given.
+type Walker struct {
+	checkers map[Kind]Checker
+}
+
+// NewWalker builds a Walker with the default checkers for iso_url,
+// checksum_url, file, and http_directory references, then layers extra on
+// top so callers can override any of them (e.g. to set a BaseDir) or add
+// checkers for builder-specific ref kinds.
+func NewWalker(extra ...Checker) *Walker {
+	w := &Walker{checkers: make(map[Kind]Checker)}
+	for _, c := range []Checker{
+		HTTPChecker{},
+		ChecksumURLChecker{},
+		FileChecker{},
+		DirectoryChecker{},
+	} {
+		w.Register(c)
+	}
+	for _, c := range extra {
+		w.Register(c)
+	}
+	return w
+}
+
+// Register adds or replaces the Checker used for a Kind.
+func (w *Walker) Register(c Checker) {
+	w.checkers[c.Kind()] = c
+}
+
+// Check runs the Checker registered for each ref's Kind, skipping refs
+// whose Kind has no registered Checker.
+func (w *Walker) Check(ctx context.Context, refs []Ref) []Result {
+	out := make([]Result, 0, len(refs))
+	for _, ref := range refs {
+		checker, ok := w.checkers[ref.Kind]
+		if !ok {
+			continue
+		}
+		out = append(out, checker.Check(ctx, ref))
+	}
+	return out
+}